@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// CredentialStoreKind identifies which CredentialStore implementation to use.
+type CredentialStoreKind string
+
+const (
+	// CredentialStoreFile stores tokens in the plaintext YAML auth config, the historic jx default.
+	CredentialStoreFile CredentialStoreKind = "file"
+	// CredentialStoreKeyring stores tokens in the OS native secret store (macOS Keychain, Secret
+	// Service on Linux, Windows Credential Manager).
+	CredentialStoreKeyring CredentialStoreKind = "keyring"
+	// CredentialStoreEnv reads tokens from the environment only and never persists them.
+	CredentialStoreEnv CredentialStoreKind = "env"
+	// CredentialStoreHelper shells out to a `git-credential-*` helper. The helper program is named
+	// by appending ":<program>" to the kind, e.g. "helper:git-credential-manager" - there is
+	// deliberately no bare default, since git's own "git-credential-store" helper writes tokens to
+	// ~/.git-credentials in plaintext.
+	CredentialStoreHelper CredentialStoreKind = "helper"
+
+	// credentialStoreHelperPrefix is the "helper:" prefix CredentialStoreHelper kinds are named with.
+	credentialStoreHelperPrefix = string(CredentialStoreHelper) + ":"
+)
+
+// keyringService is the Secret Service/Keychain "service" name jx credentials are filed under.
+const keyringService = "jx"
+
+// CredentialStore abstracts where jx reads and writes Git provider tokens, so that
+// PickNewOrExistingGitRepository is never forced to write plaintext tokens to
+// ~/.jx/gitAuth.yaml when a more secure backend is available.
+type CredentialStore interface {
+	// Kind returns the kind of this store, used for logging and diagnostics.
+	Kind() CredentialStoreKind
+
+	// LoadUserAuth returns the stored credentials for the given server/username, if any.
+	LoadUserAuth(serverURL string, username string) (*UserAuth, error)
+
+	// SaveUserAuth persists the given credentials, returning an error if the store cannot do so.
+	SaveUserAuth(serverURL string, userAuth *UserAuth) error
+}
+
+// NewCredentialStore creates the CredentialStore for the given kind. An empty kind defaults to
+// the file based store to preserve existing behaviour. A helper store must name its program as
+// "helper:<program>", e.g. "helper:git-credential-manager".
+func NewCredentialStore(kind CredentialStoreKind, configSvc ConfigService) (CredentialStore, error) {
+	if strings.HasPrefix(string(kind), credentialStoreHelperPrefix) {
+		helper := strings.TrimPrefix(string(kind), credentialStoreHelperPrefix)
+		if helper == "" {
+			return nil, fmt.Errorf("--credential-store=%s must name a helper program, e.g. %sgit-credential-manager", kind, credentialStoreHelperPrefix)
+		}
+		return &helperCredentialStore{helper: helper}, nil
+	}
+	switch kind {
+	case "", CredentialStoreFile:
+		return &fileCredentialStore{configSvc: configSvc}, nil
+	case CredentialStoreKeyring:
+		return &keyringCredentialStore{}, nil
+	case CredentialStoreEnv:
+		return &envCredentialStore{}, nil
+	case CredentialStoreHelper:
+		return nil, fmt.Errorf("--credential-store=%s must name a helper program, e.g. %sgit-credential-manager", kind, credentialStoreHelperPrefix)
+	default:
+		return nil, fmt.Errorf("unknown credential store %q", kind)
+	}
+}
+
+// fileCredentialStore is the historic jx behaviour of writing tokens into the YAML auth config.
+type fileCredentialStore struct {
+	configSvc ConfigService
+}
+
+func (s *fileCredentialStore) Kind() CredentialStoreKind {
+	return CredentialStoreFile
+}
+
+func (s *fileCredentialStore) LoadUserAuth(serverURL string, username string) (*UserAuth, error) {
+	return s.configSvc.Config().FindUserAuth(serverURL, username), nil
+}
+
+func (s *fileCredentialStore) SaveUserAuth(serverURL string, userAuth *UserAuth) error {
+	return s.configSvc.SaveUserAuth(serverURL, userAuth)
+}
+
+// keyringCredentialStore stores tokens in the OS native secret store via go-keyring.
+type keyringCredentialStore struct {
+}
+
+func (s *keyringCredentialStore) Kind() CredentialStoreKind {
+	return CredentialStoreKeyring
+}
+
+func (s *keyringCredentialStore) key(serverURL string, username string) string {
+	return serverURL + "/" + username
+}
+
+func (s *keyringCredentialStore) LoadUserAuth(serverURL string, username string) (*UserAuth, error) {
+	token, err := keyring.Get(keyringService, s.key(serverURL, username))
+	if err != nil {
+		if err == keyring.ErrNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read credentials from OS keyring: %s", err)
+	}
+	return &UserAuth{Username: username, ApiToken: token}, nil
+}
+
+func (s *keyringCredentialStore) SaveUserAuth(serverURL string, userAuth *UserAuth) error {
+	err := keyring.Set(keyringService, s.key(serverURL, userAuth.Username), userAuth.ApiToken)
+	if err != nil {
+		return fmt.Errorf("failed to save credentials to OS keyring: %s", err)
+	}
+	return nil
+}
+
+// envCredentialStore never persists anything - it only ever reflects back the token the process
+// already has (typically from JX_GIT_TOKEN), for fully ephemeral CI use.
+type envCredentialStore struct {
+}
+
+func (s *envCredentialStore) Kind() CredentialStoreKind {
+	return CredentialStoreEnv
+}
+
+func (s *envCredentialStore) LoadUserAuth(serverURL string, username string) (*UserAuth, error) {
+	token := os.Getenv("JX_GIT_TOKEN")
+	if token == "" {
+		return nil, nil
+	}
+	return &UserAuth{Username: username, ApiToken: token}, nil
+}
+
+func (s *envCredentialStore) SaveUserAuth(serverURL string, userAuth *UserAuth) error {
+	// nothing to persist - the caller already has the token in memory/environment
+	return nil
+}
+
+// helperCredentialStore shells out to a `git-credential-*` helper binary using the standard
+// git-credential protocol (https://git-scm.com/docs/git-credential).
+type helperCredentialStore struct {
+	helper string
+}
+
+func (s *helperCredentialStore) Kind() CredentialStoreKind {
+	return CredentialStoreHelper
+}
+
+func (s *helperCredentialStore) LoadUserAuth(serverURL string, username string) (*UserAuth, error) {
+	out, err := s.run("get", s.request(serverURL, username))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials from %s: %s", s.helper, err)
+	}
+	fields := parseCredentialHelperOutput(out)
+	token := fields["password"]
+	if token == "" {
+		return nil, nil
+	}
+	return &UserAuth{Username: fields["username"], ApiToken: token}, nil
+}
+
+func (s *helperCredentialStore) SaveUserAuth(serverURL string, userAuth *UserAuth) error {
+	request := s.request(serverURL, userAuth.Username) + fmt.Sprintf("password=%s\n", userAuth.ApiToken)
+	_, err := s.run("store", request)
+	if err != nil {
+		return fmt.Errorf("failed to store credentials via %s: %s", s.helper, err)
+	}
+	return nil
+}
+
+func (s *helperCredentialStore) request(serverURL string, username string) string {
+	return fmt.Sprintf("url=%s\nusername=%s\n", serverURL, username)
+}
+
+func (s *helperCredentialStore) run(action string, input string) (string, error) {
+	cmd := exec.Command(s.helper, action)
+	cmd.Stdin = strings.NewReader(input + "\n")
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func parseCredentialHelperOutput(out string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 {
+			fields[parts[0]] = parts[1]
+		}
+	}
+	return fields
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCredentialStore(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewCredentialStore(CredentialStoreKind(""), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, CredentialStoreFile, store.Kind())
+
+	store, err = NewCredentialStore(CredentialStoreKeyring, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, CredentialStoreKeyring, store.Kind())
+
+	store, err = NewCredentialStore(CredentialStoreEnv, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, CredentialStoreEnv, store.Kind())
+
+	_, err = NewCredentialStore(CredentialStoreHelper, nil)
+	assert.Error(t, err, "a bare \"helper\" kind with no program name must be rejected, not default to a plaintext helper")
+
+	store, err = NewCredentialStore(CredentialStoreKind("helper:git-credential-manager"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, CredentialStoreHelper, store.Kind())
+	helper, ok := store.(*helperCredentialStore)
+	if assert.True(t, ok) {
+		assert.Equal(t, "git-credential-manager", helper.helper)
+	}
+
+	_, err = NewCredentialStore(CredentialStoreKind("helper:"), nil)
+	assert.Error(t, err)
+
+	_, err = NewCredentialStore(CredentialStoreKind("bogus"), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package gits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x/jx/pkg/auth"
+)
+
+// BitbucketServerProvider is a GitProvider backed by the Bitbucket Server (Stash) REST API. A
+// Bitbucket "project" is the closest equivalent of a GitHub/GitLab organisation.
+type BitbucketServerProvider struct {
+	Server   *auth.AuthServer
+	Username string
+	ApiToken string
+	Client   *http.Client
+}
+
+type bitbucketProject struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// GetOrganisation looks up a Bitbucket Server project by key, returning ErrOrganisationNotFound if
+// it does not exist on this server.
+func (p *BitbucketServerProvider) GetOrganisation(name string) (*Organisation, error) {
+	resp, err := p.do(http.MethodGet, "/rest/api/1.0/projects/"+strings.ToUpper(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrOrganisationNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to get Bitbucket project %s: status %s", name, resp.Status)
+	}
+	project := &bitbucketProject{}
+	if err := json.NewDecoder(resp.Body).Decode(project); err != nil {
+		return nil, fmt.Errorf("failed to parse Bitbucket project %s: %s", name, err)
+	}
+	return &Organisation{Login: project.Key}, nil
+}
+
+// CreateOrganisation creates a new Bitbucket Server project.
+func (p *BitbucketServerProvider) CreateOrganisation(name string, description string, private bool) (*Organisation, error) {
+	body := &bitbucketProject{Key: strings.ToUpper(name), Name: name}
+	resp, err := p.do(http.MethodPost, "/rest/api/1.0/projects", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to create Bitbucket project %s: status %s", name, resp.Status)
+	}
+	return &Organisation{Login: body.Key}, nil
+}
+
+// bitbucketUser is the subset of the /rest/api/1.0/users/{username} response jx cares about.
+type bitbucketUser struct {
+	Name string `json:"name"`
+}
+
+// ValidateCredentials checks the token is valid by fetching the authenticated user. Bitbucket
+// Server doesn't expose the scopes granted to a personal access token over the REST API, so no
+// scopes are returned; callers treat a nil scope list as "can't be checked" rather than "nothing
+// granted".
+func (p *BitbucketServerProvider) ValidateCredentials(ctx context.Context) (string, []string, error) {
+	resp, err := p.do(http.MethodGet, "/rest/api/1.0/users/"+p.Username, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to validate Bitbucket credentials: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", nil, fmt.Errorf("failed to validate Bitbucket credentials: status %s", resp.Status)
+	}
+	user := &bitbucketUser{}
+	if err := json.NewDecoder(resp.Body).Decode(user); err != nil {
+		return "", nil, fmt.Errorf("failed to parse Bitbucket user: %s", err)
+	}
+	return user.Name, nil, nil
+}
+
+func (p *BitbucketServerProvider) do(method string, path string, body interface{}) (*http.Response, error) {
+	var bodyReader *strings.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal Bitbucket request: %s", err)
+		}
+		bodyReader = strings.NewReader(string(data))
+	} else {
+		bodyReader = strings.NewReader("")
+	}
+	req, err := http.NewRequest(method, p.Server.URL+path, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Bitbucket request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(p.Username, p.ApiToken)
+	return p.Client.Do(req)
+}
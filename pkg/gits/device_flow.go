@@ -0,0 +1,73 @@
+package gits
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x/jx/pkg/util"
+	"gopkg.in/AlecAivazis/survey.v1/terminal"
+)
+
+// DeviceCodeResponse is the response of a provider's device authorization endpoint, as defined by
+// the OAuth 2.0 Device Authorization Grant (RFC 8628).
+type DeviceCodeResponse struct {
+	DeviceCode      string
+	UserCode        string
+	VerificationURI string
+	ExpiresIn       int
+	Interval        int
+}
+
+// deviceFlowErrorSlowDown, deviceFlowErrorPending etc are the well known "error" values an access
+// token poll can return while the user has not yet finished authorizing the device, per RFC 8628
+// section 3.5.
+const (
+	deviceFlowErrorPending   = "authorization_pending"
+	deviceFlowErrorSlowDown  = "slow_down"
+	deviceFlowErrorExpired   = "expired_token"
+	deviceFlowErrorDenied    = "access_denied"
+	deviceFlowSlowDownPeriod = 5 * time.Second
+)
+
+// PollDeviceFlowAccessToken prints the user code and verification URI from resp then polls poll
+// (a call to the provider's access token endpoint) at the advertised interval until it returns a
+// token, the device code expires, or the user denies access. GitProvider implementations which
+// support DeviceFlowLogin should obtain a DeviceCodeResponse from their device code endpoint and
+// delegate the polling loop to this function.
+func PollDeviceFlowAccessToken(out terminal.FileWriter, resp DeviceCodeResponse, poll func() (token string, errorCode string, err error)) (string, error) {
+	fmt.Fprintf(out, "First copy your one-time code: %s\n", util.ColorInfo(resp.UserCode))
+	fmt.Fprintf(out, "Then open %s in your browser to continue...\n", util.ColorInfo(resp.VerificationURI))
+
+	interval := time.Duration(resp.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+
+	for {
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("device code expired before login was completed")
+		}
+		time.Sleep(interval)
+
+		token, errorCode, err := poll()
+		if err != nil {
+			return "", err
+		}
+		switch errorCode {
+		case "":
+			return token, nil
+		case deviceFlowErrorPending:
+			continue
+		case deviceFlowErrorSlowDown:
+			interval += deviceFlowSlowDownPeriod
+			continue
+		case deviceFlowErrorExpired:
+			return "", fmt.Errorf("device code expired before login was completed")
+		case deviceFlowErrorDenied:
+			return "", fmt.Errorf("access denied")
+		default:
+			return "", fmt.Errorf("unexpected device flow error %q", errorCode)
+		}
+	}
+}
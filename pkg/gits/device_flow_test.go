@@ -0,0 +1,109 @@
+package gits
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// devNullWriter opens /dev/null as an *os.File, which satisfies terminal.FileWriter, so tests don't
+// spam stdout with the device flow's user-facing prompts.
+func devNullWriter(t *testing.T) *os.File {
+	t.Helper()
+	f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open %s: %s", os.DevNull, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestPollDeviceFlowAccessToken(t *testing.T) {
+	t.Parallel()
+
+	out := devNullWriter(t)
+
+	t.Run("succeeds once the poll returns a token", func(t *testing.T) {
+		t.Parallel()
+		resp := DeviceCodeResponse{UserCode: "ABCD-1234", VerificationURI: "https://example.com/device", ExpiresIn: 30, Interval: 1}
+		calls := 0
+		token, err := PollDeviceFlowAccessToken(out, resp, func() (string, string, error) {
+			calls++
+			if calls < 2 {
+				return "", deviceFlowErrorPending, nil
+			}
+			return "the-token", "", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "the-token", token)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("keeps polling through slow_down", func(t *testing.T) {
+		t.Parallel()
+		resp := DeviceCodeResponse{UserCode: "ABCD-1234", VerificationURI: "https://example.com/device", ExpiresIn: 30, Interval: 1}
+		calls := 0
+		token, err := PollDeviceFlowAccessToken(out, resp, func() (string, string, error) {
+			calls++
+			switch calls {
+			case 1:
+				return "", deviceFlowErrorSlowDown, nil
+			case 2:
+				return "", deviceFlowErrorPending, nil
+			default:
+				return "the-token", "", nil
+			}
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "the-token", token)
+	})
+
+	t.Run("fails when the device code expires", func(t *testing.T) {
+		t.Parallel()
+		resp := DeviceCodeResponse{UserCode: "ABCD-1234", VerificationURI: "https://example.com/device", ExpiresIn: 30, Interval: 1}
+		_, err := PollDeviceFlowAccessToken(out, resp, func() (string, string, error) {
+			return "", deviceFlowErrorExpired, nil
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("fails when the user denies access", func(t *testing.T) {
+		t.Parallel()
+		resp := DeviceCodeResponse{UserCode: "ABCD-1234", VerificationURI: "https://example.com/device", ExpiresIn: 30, Interval: 1}
+		_, err := PollDeviceFlowAccessToken(out, resp, func() (string, string, error) {
+			return "", deviceFlowErrorDenied, nil
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("fails on an unrecognised error code", func(t *testing.T) {
+		t.Parallel()
+		resp := DeviceCodeResponse{UserCode: "ABCD-1234", VerificationURI: "https://example.com/device", ExpiresIn: 30, Interval: 1}
+		_, err := PollDeviceFlowAccessToken(out, resp, func() (string, string, error) {
+			return "", "some_unexpected_error", nil
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("propagates a transport error from poll", func(t *testing.T) {
+		t.Parallel()
+		resp := DeviceCodeResponse{UserCode: "ABCD-1234", VerificationURI: "https://example.com/device", ExpiresIn: 30, Interval: 1}
+		wantErr := fmt.Errorf("network is down")
+		_, err := PollDeviceFlowAccessToken(out, resp, func() (string, string, error) {
+			return "", "", wantErr
+		})
+		assert.Equal(t, wantErr, err)
+	})
+
+	t.Run("fails immediately if already past the deadline", func(t *testing.T) {
+		t.Parallel()
+		resp := DeviceCodeResponse{UserCode: "ABCD-1234", VerificationURI: "https://example.com/device", ExpiresIn: -1, Interval: 1}
+		_, err := PollDeviceFlowAccessToken(out, resp, func() (string, string, error) {
+			t.Fatal("poll should not be called once the deadline has already passed")
+			return "", "", nil
+		})
+		assert.Error(t, err)
+	})
+}
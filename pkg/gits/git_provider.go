@@ -0,0 +1,48 @@
+package gits
+
+import (
+	"context"
+	"errors"
+
+	"gopkg.in/AlecAivazis/survey.v1/terminal"
+)
+
+// Organisation is a Git provider organisation (or GitLab group, or Bitbucket project) as returned
+// by OrganisationCapableGitProvider.
+type Organisation struct {
+	Login string
+}
+
+// ErrOrganisationNotFound is returned by OrganisationCapableGitProvider.GetOrganisation when the named organisation
+// does not exist on the server, as distinct from an auth failure, network error or rate limit -
+// callers that need to tell "missing" apart from "couldn't check" should compare against this.
+var ErrOrganisationNotFound = errors.New("organisation not found")
+
+// OrganisationCapableGitProvider is implemented by the GitProvider backends (GitHub, GitLab, Gitea,
+// Bitbucket) in support of auto-creating an organisation that does not yet exist when picking a new
+// repository owner.
+type OrganisationCapableGitProvider interface {
+	// GetOrganisation looks up an organisation by name, returning ErrOrganisationNotFound if it
+	// does not exist on the server.
+	GetOrganisation(name string) (*Organisation, error)
+
+	// CreateOrganisation creates a new organisation on the server.
+	CreateOrganisation(name string, description string, private bool) (*Organisation, error)
+}
+
+// CredentialValidatingGitProvider is implemented by GitProvider backends that can check a token is
+// actually valid, and which scopes it carries, before jx relies on it.
+type CredentialValidatingGitProvider interface {
+	// ValidateCredentials checks the provider's credentials are valid, returning the login they
+	// resolve to and, where the provider exposes it, the scopes granted to the token.
+	ValidateCredentials(ctx context.Context) (login string, scopes []string, err error)
+}
+
+// DeviceFlowGitProvider is implemented by GitProvider backends that support the OAuth 2.0 Device
+// Authorization Grant (RFC 8628) as an alternative to pasting a personal access token.
+type DeviceFlowGitProvider interface {
+	// DeviceFlowLogin drives the device authorization flow for the given scopes, printing the
+	// one-time code and verification URL to out, and returns the resulting access token once the
+	// user has authorized the device.
+	DeviceFlowLogin(ctx context.Context, scopes []string, out terminal.FileWriter) (string, error)
+}
@@ -1,8 +1,11 @@
 package gits
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"net/url"
+	"os"
 	"strings"
 
 	"github.com/jenkins-x/jx/pkg/auth"
@@ -11,6 +14,18 @@ import (
 	"gopkg.in/AlecAivazis/survey.v1/terminal"
 )
 
+// JXGitTokenEnvVar is the environment variable jx reads a Git API token from when one is not
+// supplied via --git-api-token, to support fully unattended repository creation from CI.
+const JXGitTokenEnvVar = "JX_GIT_TOKEN"
+
+// knownGitHosts maps well known Git host names to their ServerKind so that a bare "host:owner/repo"
+// spec doesn't need the user to specify --git-server explicitly.
+var knownGitHosts = map[string]string{
+	"github.com":    KindGitHub,
+	"gitlab.com":    KindGitlab,
+	"bitbucket.org": KindBitBucketCloud,
+}
+
 type CreateRepoData struct {
 	Organisation string
 	RepoName     string
@@ -22,6 +37,12 @@ type CreateRepoData struct {
 }
 
 type GitRepositoryOptions struct {
+	// Spec is a single "host:owner/repo" style spec as accepted by ParseGitRepositoryOptions, for
+	// non-interactive repository creation from one argument. When set, PickNewOrExistingGitRepository
+	// parses it and uses the result to fill in any of ServerURL/ServerKind/Owner/RepoName/Private
+	// that weren't already set explicitly (e.g. via --git-server/--org/--name/--private).
+	Spec string
+
 	ServerURL  string
 	ServerKind string
 	Username   string
@@ -29,6 +50,109 @@ type GitRepositoryOptions struct {
 	Owner      string
 	RepoName   string
 	Private    bool
+	CreateOrg  bool
+
+	// CredentialStore selects which auth.CredentialStore backend is used to load/save the user's
+	// Git token, e.g. "file" (default), "keyring", "env" or "helper:<program>". See --credential-store.
+	CredentialStore string
+
+	// LoginMode is "token" (default) to paste a personal access token, or "device" to use the
+	// OAuth 2.0 device authorization flow instead. The device flow is only used in interactive mode
+	// against GitHub and GitLab servers; otherwise the token prompt is used. See --login.
+	LoginMode string
+}
+
+// deviceFlowKinds are the ServerKind values whose GitProvider supports DeviceFlowLogin.
+var deviceFlowKinds = map[string]bool{
+	KindGitHub: true,
+	KindGitlab: true,
+}
+
+// ParseGitRepositoryOptions parses a single spec string into a GitRepositoryOptions so that batch
+// mode repository creation can be driven from one argument instead of a pre-populated auth config
+// plus separate --git-server/--org/--name flags. Supported forms are a full URL
+// (https://github.com/acme/widgets), a short host form (github.com:acme/widgets) and the
+// actions-sync-style rename form (upstream/repo:acme/widgets) which only keeps the destination
+// owner/repo. A trailing "?private=true" marks the new repository as private.
+func ParseGitRepositoryOptions(spec string) (*GitRepositoryOptions, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("no git repository spec specified")
+	}
+
+	private := false
+	if idx := strings.Index(spec, "?"); idx >= 0 {
+		query, err := url.ParseQuery(spec[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse query in git repository spec %s: %s", spec, err)
+		}
+		private = query.Get("private") == "true"
+		spec = spec[:idx]
+	}
+
+	options := &GitRepositoryOptions{Private: private}
+
+	if strings.Contains(spec, "://") {
+		u, err := url.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse git repository URL %s: %s", spec, err)
+		}
+		owner, repoName, err := parseOwnerRepo(strings.Trim(u.Path, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse git repository spec %s: %s", spec, err)
+		}
+		options.ServerURL = u.Scheme + "://" + u.Host
+		options.ServerKind = knownGitHosts[u.Host]
+		options.Owner = owner
+		options.RepoName = repoName
+		return options, nil
+	}
+
+	host, ownerRepo, err := splitHostOwnerRepo(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse git repository spec %s: %s", spec, err)
+	}
+	owner, repoName, err := parseOwnerRepo(ownerRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse git repository spec %s: %s", spec, err)
+	}
+	if host != "" {
+		options.ServerURL = "https://" + host
+		options.ServerKind = knownGitHosts[host]
+	}
+	options.Owner = owner
+	options.RepoName = repoName
+	return options, nil
+}
+
+// splitHostOwnerRepo splits a "host:owner/repo" or actions-sync-style "upstream/repo:owner/repo"
+// spec into the host part (empty if not present) and the destination "owner/repo" part.
+func splitHostOwnerRepo(spec string) (string, string, error) {
+	idx := strings.LastIndex(spec, ":")
+	if idx < 0 {
+		return "", spec, nil
+	}
+	before := spec[:idx]
+	after := spec[idx+1:]
+	if _, known := knownGitHosts[before]; known {
+		return before, after, nil
+	}
+	if strings.Contains(before, "/") {
+		// actions-sync-style rename form "upstream/repo:acme/widgets" - we only care about
+		// the destination owner/repo, the source is only relevant to the mirror-sync tool.
+		return "", after, nil
+	}
+	return before, after, nil
+}
+
+// parseOwnerRepo splits an "owner/repo" string, returning an error if either part is missing. A
+// trailing ".git" on the repo name (as in "acme/widgets.git") is stripped.
+func parseOwnerRepo(ownerRepo string) (string, string, error) {
+	parts := strings.Split(ownerRepo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("expected owner/repo but got %s", ownerRepo)
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), nil
 }
 
 // GetRepository returns the repository if it already exists
@@ -46,6 +170,27 @@ func PickNewOrExistingGitRepository(batchMode bool, authConfigSvc auth.ConfigSer
 	config := authConfigSvc.Config()
 
 	var err error
+	if repoOptions.Spec != "" {
+		parsed, err := ParseGitRepositoryOptions(repoOptions.Spec)
+		if err != nil {
+			return nil, err
+		}
+		if repoOptions.ServerURL == "" {
+			repoOptions.ServerURL = parsed.ServerURL
+		}
+		if repoOptions.ServerKind == "" {
+			repoOptions.ServerKind = parsed.ServerKind
+		}
+		if repoOptions.Owner == "" {
+			repoOptions.Owner = parsed.Owner
+		}
+		if repoOptions.RepoName == "" {
+			repoOptions.RepoName = parsed.RepoName
+		}
+		if !repoOptions.Private {
+			repoOptions.Private = parsed.Private
+		}
+	}
 	if server == nil {
 		if repoOptions.ServerURL != "" {
 			server = config.GetOrCreateServer(repoOptions.ServerURL)
@@ -77,6 +222,18 @@ func PickNewOrExistingGitRepository(batchMode bool, authConfigSvc auth.ConfigSer
 	fmt.Fprintf(out, "Using Git provider %s\n", util.ColorInfo(server.Description()))
 	url := server.URL
 
+	credentialStore, err := auth.NewCredentialStore(auth.CredentialStoreKind(repoOptions.CredentialStore), authConfigSvc)
+	if err != nil {
+		return nil, err
+	}
+
+	if userAuth == nil && repoOptions.Username != "" {
+		userAuth, err = credentialStore.LoadUserAuth(url, repoOptions.Username)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if userAuth == nil {
 		if repoOptions.Username != "" {
 			userAuth = config.GetOrCreateUserAuth(url, repoOptions.Username)
@@ -102,26 +259,47 @@ func PickNewOrExistingGitRepository(batchMode bool, authConfigSvc auth.ConfigSer
 		}
 	}
 
+	if repoOptions.ApiToken == "" {
+		repoOptions.ApiToken = os.Getenv(JXGitTokenEnvVar)
+	}
 	if userAuth.IsInvalid() && repoOptions.ApiToken != "" {
 		userAuth.ApiToken = repoOptions.ApiToken
 	}
 
 	if userAuth.IsInvalid() {
-		f := func(username string) error {
-			git.PrintCreateRepositoryGenerateAccessToken(server, username, out)
-			return nil
-		}
+		if !batchMode && repoOptions.LoginMode == "device" && deviceFlowKinds[server.Kind] {
+			token, err := deviceFlowLogin(server, git, requiredScopesForRepoCreation(repoOptions.Owner, ""), out)
+			if err != nil {
+				return nil, err
+			}
+			userAuth.ApiToken = token
+		} else {
+			f := func(username string) error {
+				git.PrintCreateRepositoryGenerateAccessToken(server, username, out)
+				return nil
+			}
 
-		// TODO could we guess this based on the users ~/.git for github?
-		defaultUserName := ""
-		err = config.EditUserAuth(server.Label(), userAuth, defaultUserName, true, batchMode, f, in, out, errOut)
-		if err != nil {
-			return nil, err
-		}
+			// TODO could we guess this based on the users ~/.git for github?
+			defaultUserName := ""
+			for {
+				err = config.EditUserAuth(server.Label(), userAuth, defaultUserName, true, batchMode, f, in, out, errOut)
+				if err != nil {
+					return nil, err
+				}
 
-		// TODO lets verify the auth works
+				err = validateUserAuth(server, userAuth, git, repoOptions.Owner)
+				if err == nil {
+					break
+				}
+				fmt.Fprintf(errOut, "%s\n", util.ColorError(err))
+				if batchMode {
+					return nil, err
+				}
+				userAuth.Invalidate()
+			}
+		}
 
-		err = authConfigSvc.SaveUserAuth(url, userAuth)
+		err = credentialStore.SaveUserAuth(url, userAuth)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to store git auth configuration %s", err)
 		}
@@ -144,6 +322,16 @@ func PickNewOrExistingGitRepository(batchMode bool, authConfigSvc auth.ConfigSer
 			return nil, err
 		}
 	}
+	err = validateScopesForOwner(provider, gitUsername, owner)
+	if err != nil {
+		return nil, err
+	}
+	if owner != gitUsername {
+		err = EnsureOrganisationExists(owner, repoOptions.CreateOrg, batchMode, provider, in, out, errOut)
+		if err != nil {
+			return nil, err
+		}
+	}
 	repoName := repoOptions.RepoName
 	if repoName == "" {
 		repoName, err = GetRepoName(batchMode, allowExistingRepo, provider, defaultRepoName, owner, in, out, errOut)
@@ -166,6 +354,104 @@ func PickNewOrExistingGitRepository(batchMode bool, authConfigSvc auth.ConfigSer
 	}, err
 }
 
+// validateUserAuth builds a provider from the given credentials and checks that they actually work,
+// including that the token carries the scopes jx needs to create a repository (and, if owner is
+// already known to differ from the authenticated user, to administer that organisation). owner may
+// be "" if the destination owner isn't known yet - validateScopesForOwner re-checks the admin:org
+// scope once it is.
+func validateUserAuth(server *auth.AuthServer, userAuth *auth.UserAuth, git Gitter, owner string) error {
+	provider, err := CreateProvider(server, userAuth, git)
+	if err != nil {
+		return fmt.Errorf("failed to create Git provider: %s", err)
+	}
+	validator, ok := provider.(CredentialValidatingGitProvider)
+	if !ok {
+		// this provider can't pre-flight credentials; any auth problem will surface later when
+		// we actually call the API
+		return nil
+	}
+	login, scopes, err := validator.ValidateCredentials(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to validate Git credentials: %s", err)
+	}
+	return checkRequiredScopes(login, scopes, owner, login)
+}
+
+// validateScopesForOwner re-runs the token scope check once the destination owner is known, for the
+// common interactive path where it wasn't known yet when validateUserAuth first ran. It always
+// re-checks the baseline repo/write:public_key scopes - including when owner == gitUsername, the
+// most common case - and only adds the admin:org check when the repository is being created under
+// an organisation other than the authenticated user's own account.
+func validateScopesForOwner(provider GitProvider, gitUsername string, owner string) error {
+	validator, ok := provider.(CredentialValidatingGitProvider)
+	if !ok {
+		return nil
+	}
+	_, scopes, err := validator.ValidateCredentials(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to validate Git credentials: %s", err)
+	}
+	return checkRequiredScopes(gitUsername, scopes, owner, gitUsername)
+}
+
+// checkRequiredScopes compares the scopes a token carries against what's needed to create a
+// repository for the given owner/gitUsername pair. A nil scopes list means the provider doesn't
+// expose scope metadata (e.g. Gitea) - that's treated as "can't be checked", not "nothing granted".
+func checkRequiredScopes(login string, scopes []string, owner string, gitUsername string) error {
+	if scopes == nil {
+		return nil
+	}
+	missing := missingScopes(scopes, requiredScopesForRepoCreation(owner, gitUsername))
+	if len(missing) > 0 {
+		return fmt.Errorf("the token for %s is missing the scopes %s required to create repositories", login, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// deviceFlowLogin drives the OAuth 2.0 device authorization flow against server, as an alternative
+// to PrintCreateRepositoryGenerateAccessToken's copy-paste-a-token dance.
+func deviceFlowLogin(server *auth.AuthServer, git Gitter, scopes []string, out terminal.FileWriter) (string, error) {
+	provider, err := CreateProvider(server, &auth.UserAuth{}, git)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Git provider for device login: %s", err)
+	}
+	deviceProvider, ok := provider.(DeviceFlowGitProvider)
+	if !ok {
+		return "", fmt.Errorf("the Git provider at %s does not support device login", server.URL)
+	}
+	token, err := deviceProvider.DeviceFlowLogin(context.Background(), scopes, out)
+	if err != nil {
+		return "", fmt.Errorf("device login failed: %s", err)
+	}
+	return token, nil
+}
+
+// requiredScopesForRepoCreation returns the token scopes jx needs for the repository creation the
+// user has requested. admin:org is only required when the repository is being created under an
+// organisation other than the authenticated user's own account.
+func requiredScopesForRepoCreation(owner string, gitUsername string) []string {
+	scopes := []string{"repo", "write:public_key"}
+	if owner != "" && owner != gitUsername {
+		scopes = append(scopes, "admin:org")
+	}
+	return scopes
+}
+
+// missingScopes returns the entries in required which are not present in have.
+func missingScopes(have []string, required []string) []string {
+	set := map[string]bool{}
+	for _, s := range have {
+		set[s] = true
+	}
+	var missing []string
+	for _, r := range required {
+		if !set[r] {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
 func GetRepoName(batchMode, allowExistingRepo bool, provider GitProvider, defaultRepoName, owner string, in terminal.FileReader, out terminal.FileWriter, errOut io.Writer) (string, error) {
 	surveyOpts := survey.WithStdio(in, out, errOut)
 	repoName := ""
@@ -220,6 +506,51 @@ func GetOwner(batchMode bool, provider GitProvider, gitUsername string, in termi
 	return owner, nil
 }
 
+// EnsureOrganisationExists checks that the given organisation exists on the provider and, if it does
+// not, creates it - without asking when createOrg is true, otherwise after prompting the user
+// interactively. In batch mode without createOrg a missing organisation is an error.
+func EnsureOrganisationExists(owner string, createOrg bool, batchMode bool, provider GitProvider, in terminal.FileReader, out terminal.FileWriter, errOut io.Writer) error {
+	orgProvider, ok := provider.(OrganisationCapableGitProvider)
+	if !ok {
+		return fmt.Errorf("the Git provider at %s does not support creating organisations", provider.ServerURL())
+	}
+
+	_, err := orgProvider.GetOrganisation(owner)
+	if err == nil {
+		// organisation already exists
+		return nil
+	}
+	if err != ErrOrganisationNotFound {
+		return fmt.Errorf("failed to check if organisation %s exists on %s: %s", owner, provider.ServerURL(), err)
+	}
+
+	create := createOrg
+	if !create {
+		if batchMode {
+			return fmt.Errorf("organisation %s does not exist on %s; rerun with --create-org to create it automatically", owner, provider.ServerURL())
+		}
+		surveyOpts := survey.WithStdio(in, out, errOut)
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Organisation %s does not exist. Would you like to create it?", owner),
+			Default: true,
+		}
+		err = survey.AskOne(prompt, &create, nil, surveyOpts)
+		if err != nil {
+			return err
+		}
+		if !create {
+			return fmt.Errorf("organisation %s does not exist", owner)
+		}
+	}
+
+	fmt.Fprintf(out, "Creating organisation %s on %s\n", util.ColorInfo(owner), util.ColorInfo(provider.ServerURL()))
+	_, err = orgProvider.CreateOrganisation(owner, "", false)
+	if err != nil {
+		return fmt.Errorf("failed to create organisation %s: %s", owner, err)
+	}
+	return nil
+}
+
 func PickNewGitRepository(batchMode bool, authConfigSvc auth.ConfigService, defaultRepoName string,
 	repoOptions *GitRepositoryOptions, server *auth.AuthServer, userAuth *auth.UserAuth, git Gitter, in terminal.FileReader, out terminal.FileWriter, outErr io.Writer) (*CreateRepoData, error) {
 	return PickNewOrExistingGitRepository(batchMode, authConfigSvc, defaultRepoName, repoOptions, server, userAuth, git, false, in, out, outErr)
@@ -0,0 +1,218 @@
+package gits
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGitRepositoryOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		spec          string
+		wantServerURL string
+		wantOwner     string
+		wantRepoName  string
+		wantPrivate   bool
+		wantErr       bool
+	}{
+		{
+			name:          "https url",
+			spec:          "https://github.com/acme/widgets",
+			wantServerURL: "https://github.com",
+			wantOwner:     "acme",
+			wantRepoName:  "widgets",
+		},
+		{
+			name:          "https url with trailing .git",
+			spec:          "https://github.com/acme/widgets.git",
+			wantServerURL: "https://github.com",
+			wantOwner:     "acme",
+			wantRepoName:  "widgets",
+		},
+		{
+			name:          "https url with private query string",
+			spec:          "https://github.com/acme/widgets?private=true",
+			wantServerURL: "https://github.com",
+			wantOwner:     "acme",
+			wantRepoName:  "widgets",
+			wantPrivate:   true,
+		},
+		{
+			name:          "host:owner/repo form",
+			spec:          "github.com:acme/widgets",
+			wantServerURL: "https://github.com",
+			wantOwner:     "acme",
+			wantRepoName:  "widgets",
+		},
+		{
+			name:         "owner/repo with no host",
+			spec:         "acme/widgets",
+			wantOwner:    "acme",
+			wantRepoName: "widgets",
+		},
+		{
+			name:         "actions-sync rename form",
+			spec:         "upstream/widgets:acme/widgets",
+			wantOwner:    "acme",
+			wantRepoName: "widgets",
+		},
+		{
+			name:    "empty spec",
+			spec:    "",
+			wantErr: true,
+		},
+		{
+			name:    "missing repo name",
+			spec:    "acme/",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			options, err := ParseGitRepositoryOptions(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantOwner, options.Owner)
+			assert.Equal(t, tt.wantRepoName, options.RepoName)
+			assert.Equal(t, tt.wantPrivate, options.Private)
+			if tt.wantServerURL != "" {
+				assert.Equal(t, tt.wantServerURL, options.ServerURL)
+			}
+		})
+	}
+}
+
+func TestSplitHostOwnerRepo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		spec      string
+		wantHost  string
+		wantOwner string
+		wantErr   bool
+	}{
+		{"known host", "github.com:acme/widgets", "github.com", "acme/widgets", false},
+		{"no host", "acme/widgets", "", "acme/widgets", false},
+		{"actions-sync rename form", "upstream/widgets:acme/widgets", "", "acme/widgets", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			host, ownerRepo, err := splitHostOwnerRepo(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantHost, host)
+			assert.Equal(t, tt.wantOwner, ownerRepo)
+		})
+	}
+}
+
+func TestParseOwnerRepo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		ownerRepo string
+		wantOwner string
+		wantRepo  string
+		wantErr   bool
+	}{
+		{"plain owner/repo", "acme/widgets", "acme", "widgets", false},
+		{"trailing .git suffix", "acme/widgets.git", "acme", "widgets", false},
+		{"missing repo", "acme/", "", "", true},
+		{"missing owner", "/widgets", "", "", true},
+		{"no slash", "widgets", "", "", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			owner, repo, err := parseOwnerRepo(tt.ownerRepo)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOwner, owner)
+			assert.Equal(t, tt.wantRepo, repo)
+		})
+	}
+}
+
+func TestRequiredScopesForRepoCreation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		owner       string
+		gitUsername string
+		want        []string
+	}{
+		{"no owner yet", "", "djones", []string{"repo", "write:public_key"}},
+		{"owner is the authenticated user", "djones", "djones", []string{"repo", "write:public_key"}},
+		{"owner is an organisation", "acme", "djones", []string{"repo", "write:public_key", "admin:org"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, requiredScopesForRepoCreation(tt.owner, tt.gitUsername))
+		})
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		have     []string
+		required []string
+		want     []string
+	}{
+		{"has everything", []string{"repo", "admin:org", "write:public_key"}, []string{"repo", "admin:org"}, nil},
+		{"missing one", []string{"repo"}, []string{"repo", "admin:org"}, []string{"admin:org"}},
+		{"missing all", []string{}, []string{"repo", "admin:org"}, []string{"repo", "admin:org"}},
+		{"nothing required", []string{"repo"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, missingScopes(tt.have, tt.required))
+		})
+	}
+}
+
+func TestCheckRequiredScopes(t *testing.T) {
+	t.Parallel()
+
+	err := checkRequiredScopes("djones", nil, "acme", "djones")
+	assert.NoError(t, err, "a nil scope list means the provider can't be checked, not that everything is missing")
+
+	err = checkRequiredScopes("djones", []string{"repo"}, "acme", "djones")
+	assert.Error(t, err, "missing admin:org for an organisation owner should be reported")
+
+	err = checkRequiredScopes("djones", []string{"repo", "write:public_key", "admin:org"}, "acme", "djones")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,62 @@
+package gits
+
+import (
+	"context"
+	"fmt"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/jenkins-x/jx/pkg/auth"
+)
+
+// GiteaProvider is a GitProvider backed by the Gitea REST API.
+type GiteaProvider struct {
+	Server   *auth.AuthServer
+	Username string
+	Client   *gitea.Client
+}
+
+// GetOrganisation looks up a Gitea organisation by name, returning ErrOrganisationNotFound if it
+// does not exist on this server.
+func (p *GiteaProvider) GetOrganisation(name string) (*Organisation, error) {
+	org, err := p.Client.GetOrg(name)
+	if err != nil {
+		if isGiteaNotFound(err) {
+			return nil, ErrOrganisationNotFound
+		}
+		return nil, fmt.Errorf("failed to get Gitea organisation %s: %s", name, err)
+	}
+	return &Organisation{Login: org.UserName}, nil
+}
+
+// CreateOrganisation creates a new Gitea organisation.
+func (p *GiteaProvider) CreateOrganisation(name string, description string, private bool) (*Organisation, error) {
+	visibility := gitea.VisibleTypePublic
+	if private {
+		visibility = gitea.VisibleTypePrivate
+	}
+	org, err := p.Client.AdminCreateOrg(p.Username, gitea.CreateOrgOption{
+		UserName:    name,
+		Description: description,
+		Visibility:  visibility,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gitea organisation %s: %s", name, err)
+	}
+	return &Organisation{Login: org.UserName}, nil
+}
+
+// isGiteaNotFound reports whether err is the error the Gitea SDK returns for a 404 response.
+func isGiteaNotFound(err error) bool {
+	return err != nil && err.Error() == "404 Not Found"
+}
+
+// ValidateCredentials checks the token is valid by fetching the authenticated user from /user.
+// Gitea personal access tokens don't carry distinct OAuth-style scopes, so no scopes are returned;
+// callers treat a nil scope list as "can't be checked" rather than "everything missing".
+func (p *GiteaProvider) ValidateCredentials(ctx context.Context) (string, []string, error) {
+	user, err := p.Client.GetMyUserInfo()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to validate Gitea credentials: %s", err)
+	}
+	return user.UserName, nil, nil
+}
@@ -0,0 +1,192 @@
+package gits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/jenkins-x/jx/pkg/auth"
+	"gopkg.in/AlecAivazis/survey.v1/terminal"
+)
+
+// JXGithubDeviceFlowClientIDEnvVar overrides defaultGithubDeviceFlowClientID with the client ID of
+// a real GitHub OAuth App registered for device flow login. GitHub issues this value per
+// registered app - it is not a free-choice string - so a real login requires setting this env var
+// to jx's actual registered client ID.
+const JXGithubDeviceFlowClientIDEnvVar = "JX_GITHUB_DEVICE_FLOW_CLIENT_ID"
+
+// defaultGithubDeviceFlowClientID is a placeholder client ID. It must be overridden via
+// JXGithubDeviceFlowClientIDEnvVar with jx's real registered GitHub OAuth App client ID before
+// device flow login will work against the real GitHub API.
+const defaultGithubDeviceFlowClientID = "jx-cli-device-flow"
+
+// githubDeviceFlowClientID returns the GitHub OAuth App client ID to use for device flow login.
+func githubDeviceFlowClientID() string {
+	if id := os.Getenv(JXGithubDeviceFlowClientIDEnvVar); id != "" {
+		return id
+	}
+	return defaultGithubDeviceFlowClientID
+}
+
+// GitHubProvider is a GitProvider backed by the GitHub (or GitHub Enterprise Server) REST API.
+type GitHubProvider struct {
+	Server   *auth.AuthServer
+	Username string
+	Client   *github.Client
+}
+
+// GetOrganisation looks up a GitHub organisation by login, returning ErrOrganisationNotFound if it
+// does not exist on this server.
+func (p *GitHubProvider) GetOrganisation(name string) (*Organisation, error) {
+	org, resp, err := p.Client.Organizations.Get(context.Background(), name)
+	if resp != nil && resp.StatusCode == 404 {
+		return nil, ErrOrganisationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub organisation %s: %s", name, err)
+	}
+	return &Organisation{Login: org.GetLogin()}, nil
+}
+
+// CreateOrganisation creates a new GitHub organisation. This requires a GitHub Enterprise Server
+// admin token - github.com does not support creating organisations via the API.
+func (p *GitHubProvider) CreateOrganisation(name string, description string, private bool) (*Organisation, error) {
+	org := &github.Organization{Login: github.String(name)}
+	if description != "" {
+		org.Description = github.String(description)
+	}
+	created, _, err := p.Client.Admin.CreateOrg(context.Background(), org, p.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub organisation %s: %s", name, err)
+	}
+	return &Organisation{Login: created.GetLogin()}, nil
+}
+
+// ValidateCredentials checks the token is valid by fetching the authenticated user, and reads the
+// granted scopes back from the X-OAuth-Scopes response header.
+func (p *GitHubProvider) ValidateCredentials(ctx context.Context) (string, []string, error) {
+	user, resp, err := p.Client.Users.Get(ctx, "")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to validate GitHub credentials: %s", err)
+	}
+	var scopes []string
+	if resp != nil {
+		if values, ok := resp.Header["X-Oauth-Scopes"]; ok {
+			scopes = []string{}
+			for _, scope := range strings.Split(strings.Join(values, ","), ",") {
+				scope = strings.TrimSpace(scope)
+				if scope != "" {
+					scopes = append(scopes, scope)
+				}
+			}
+		}
+	}
+	return user.GetLogin(), scopes, nil
+}
+
+// githubDeviceCodeResponse is the response body of GitHub's POST /login/device/code endpoint.
+type githubDeviceCodeResponse struct {
+	DeviceCode       string `json:"device_code"`
+	UserCode         string `json:"user_code"`
+	VerificationURI  string `json:"verification_uri"`
+	ExpiresIn        int    `json:"expires_in"`
+	Interval         int    `json:"interval"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// githubAccessTokenResponse is the response body of GitHub's POST /login/oauth/access_token
+// endpoint when polled during the device flow.
+type githubAccessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	Error       string `json:"error"`
+}
+
+// DeviceFlowLogin drives the OAuth 2.0 device authorization flow against github.com (or a GitHub
+// Enterprise Server, using its own device flow endpoints).
+func (p *GitHubProvider) DeviceFlowLogin(ctx context.Context, scopes []string, out terminal.FileWriter) (string, error) {
+	codeResp, err := githubRequestDeviceCode(ctx, p.Server.URL, scopes)
+	if err != nil {
+		return "", fmt.Errorf("failed to request a GitHub device code: %s", err)
+	}
+
+	resp := DeviceCodeResponse{
+		DeviceCode:      codeResp.DeviceCode,
+		UserCode:        codeResp.UserCode,
+		VerificationURI: codeResp.VerificationURI,
+		ExpiresIn:       codeResp.ExpiresIn,
+		Interval:        codeResp.Interval,
+	}
+	return PollDeviceFlowAccessToken(out, resp, func() (string, string, error) {
+		token, err := githubPollDeviceAccessToken(ctx, p.Server.URL, codeResp.DeviceCode)
+		if err != nil {
+			return "", "", err
+		}
+		if token.Error != "" {
+			return "", token.Error, nil
+		}
+		return token.AccessToken, "", nil
+	})
+}
+
+func githubRequestDeviceCode(ctx context.Context, serverURL string, scopes []string) (*githubDeviceCodeResponse, error) {
+	form := url.Values{
+		"client_id": {githubDeviceFlowClientID()},
+		"scope":     {strings.Join(scopes, " ")},
+	}
+	var resp githubDeviceCodeResponse
+	statusCode, err := githubDeviceFlowPost(ctx, serverURL, "/login/device/code", form, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s: %s", resp.Error, resp.ErrorDescription)
+	}
+	if statusCode != http.StatusOK || resp.DeviceCode == "" {
+		return nil, fmt.Errorf("unexpected response (status %d) requesting a device code", statusCode)
+	}
+	return &resp, nil
+}
+
+func githubPollDeviceAccessToken(ctx context.Context, serverURL string, deviceCode string) (*githubAccessTokenResponse, error) {
+	form := url.Values{
+		"client_id":   {githubDeviceFlowClientID()},
+		"device_code": {deviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	var resp githubAccessTokenResponse
+	if _, err := githubDeviceFlowPost(ctx, serverURL, "/login/oauth/access_token", form, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// githubDeviceFlowPost posts form to path on serverURL and decodes the JSON response into out,
+// returning the response status code so callers that need to detect a rejected request (e.g. the
+// device code request, which GitHub can fail without a non-2xx status) can inspect it. Device flow
+// endpoints live under the web host, not the REST API host the go-github client talks to, so this
+// uses a plain net/http request rather than p.Client.
+func githubDeviceFlowPost(ctx context.Context, serverURL string, path string, form url.Values, out interface{}) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(serverURL, "/")+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,203 @@
+package gits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x/jx/pkg/auth"
+	gitlab "github.com/xanzy/go-gitlab"
+	"gopkg.in/AlecAivazis/survey.v1/terminal"
+)
+
+// JXGitlabDeviceFlowClientIDEnvVar overrides defaultGitlabDeviceFlowClientID with the application
+// ID of a real GitLab OAuth application registered for device flow login. GitLab issues this value
+// per registered application - it is not a free-choice string - so a real login requires setting
+// this env var to jx's actual registered application ID.
+const JXGitlabDeviceFlowClientIDEnvVar = "JX_GITLAB_DEVICE_FLOW_CLIENT_ID"
+
+// defaultGitlabDeviceFlowClientID is a placeholder application ID. It must be overridden via
+// JXGitlabDeviceFlowClientIDEnvVar with jx's real registered GitLab application ID before device
+// flow login will work against the real GitLab API.
+const defaultGitlabDeviceFlowClientID = "jx-cli-device-flow"
+
+// gitlabDeviceFlowClientID returns the GitLab application ID to use for device flow login.
+func gitlabDeviceFlowClientID() string {
+	if id := os.Getenv(JXGitlabDeviceFlowClientIDEnvVar); id != "" {
+		return id
+	}
+	return defaultGitlabDeviceFlowClientID
+}
+
+// GitLabProvider is a GitProvider backed by the GitLab REST API. GitLab has no separate
+// "organisation" concept - a top level group is the closest equivalent.
+type GitLabProvider struct {
+	Server   *auth.AuthServer
+	Username string
+	Client   *gitlab.Client
+}
+
+// GetOrganisation looks up a GitLab group by path, returning ErrOrganisationNotFound if it does
+// not exist on this server.
+func (p *GitLabProvider) GetOrganisation(name string) (*Organisation, error) {
+	group, resp, err := p.Client.Groups.GetGroup(name)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, ErrOrganisationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitLab group %s: %s", name, err)
+	}
+	return &Organisation{Login: group.Path}, nil
+}
+
+// CreateOrganisation creates a new top level GitLab group.
+func (p *GitLabProvider) CreateOrganisation(name string, description string, private bool) (*Organisation, error) {
+	visibility := gitlab.PublicVisibility
+	if private {
+		visibility = gitlab.PrivateVisibility
+	}
+	group, _, err := p.Client.Groups.CreateGroup(&gitlab.CreateGroupOptions{
+		Name:        gitlab.String(name),
+		Path:        gitlab.String(name),
+		Description: gitlab.String(description),
+		Visibility:  &visibility,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab group %s: %s", name, err)
+	}
+	return &Organisation{Login: group.Path}, nil
+}
+
+// gitlabPersonalAccessToken is the subset of the /personal_access_tokens/self response jx cares
+// about.
+type gitlabPersonalAccessToken struct {
+	Scopes []string `json:"scopes"`
+}
+
+// ValidateCredentials checks the token is valid by fetching the authenticated user, and reads the
+// granted scopes back from the /personal_access_tokens/self endpoint.
+func (p *GitLabProvider) ValidateCredentials(ctx context.Context) (string, []string, error) {
+	user, _, err := p.Client.Users.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to validate GitLab credentials: %s", err)
+	}
+
+	req, err := p.Client.NewRequest(http.MethodGet, "personal_access_tokens/self", nil, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		return user.Username, nil, fmt.Errorf("failed to build GitLab token scope request: %s", err)
+	}
+	token := &gitlabPersonalAccessToken{}
+	_, err = p.Client.Do(req, token)
+	if err != nil {
+		// older self-managed GitLab instances don't expose this endpoint - fall back to no
+		// scope information rather than failing validation outright
+		return user.Username, nil, nil
+	}
+	return user.Username, token.Scopes, nil
+}
+
+// gitlabDeviceCodeResponse is the response body of GitLab's POST /oauth/authorize_device endpoint.
+type gitlabDeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+	Error                   string `json:"error"`
+	ErrorDescription        string `json:"error_description"`
+}
+
+// gitlabAccessTokenResponse is the response body of GitLab's POST /oauth/token endpoint when
+// polled during the device flow.
+type gitlabAccessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	Error       string `json:"error"`
+}
+
+// DeviceFlowLogin drives the OAuth 2.0 device authorization flow against this GitLab instance.
+func (p *GitLabProvider) DeviceFlowLogin(ctx context.Context, scopes []string, out terminal.FileWriter) (string, error) {
+	codeResp, err := gitlabRequestDeviceCode(ctx, p.Server.URL, scopes)
+	if err != nil {
+		return "", fmt.Errorf("failed to request a GitLab device code: %s", err)
+	}
+
+	resp := DeviceCodeResponse{
+		DeviceCode:      codeResp.DeviceCode,
+		UserCode:        codeResp.UserCode,
+		VerificationURI: codeResp.VerificationURI,
+		ExpiresIn:       codeResp.ExpiresIn,
+		Interval:        codeResp.Interval,
+	}
+	return PollDeviceFlowAccessToken(out, resp, func() (string, string, error) {
+		token, err := gitlabPollDeviceAccessToken(ctx, p.Server.URL, codeResp.DeviceCode)
+		if err != nil {
+			return "", "", err
+		}
+		if token.Error != "" {
+			return "", token.Error, nil
+		}
+		return token.AccessToken, "", nil
+	})
+}
+
+func gitlabRequestDeviceCode(ctx context.Context, serverURL string, scopes []string) (*gitlabDeviceCodeResponse, error) {
+	form := url.Values{
+		"client_id": {gitlabDeviceFlowClientID()},
+		"scope":     {strings.Join(scopes, " ")},
+	}
+	var resp gitlabDeviceCodeResponse
+	statusCode, err := gitlabDeviceFlowPost(ctx, serverURL, "/oauth/authorize_device", form, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s: %s", resp.Error, resp.ErrorDescription)
+	}
+	if statusCode != http.StatusOK || resp.DeviceCode == "" {
+		return nil, fmt.Errorf("unexpected response (status %d) requesting a device code", statusCode)
+	}
+	return &resp, nil
+}
+
+func gitlabPollDeviceAccessToken(ctx context.Context, serverURL string, deviceCode string) (*gitlabAccessTokenResponse, error) {
+	form := url.Values{
+		"client_id":   {gitlabDeviceFlowClientID()},
+		"device_code": {deviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	var resp gitlabAccessTokenResponse
+	if _, err := gitlabDeviceFlowPost(ctx, serverURL, "/oauth/token", form, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// gitlabDeviceFlowPost posts form to path on serverURL and decodes the JSON response into out,
+// returning the response status code so callers that need to detect a rejected request (e.g. the
+// device code request, which GitLab can fail without a non-2xx status) can inspect it. The device
+// flow endpoints live on the GitLab web host, so this uses a plain net/http request rather than
+// p.Client, which talks to the REST API host.
+func gitlabDeviceFlowPost(ctx context.Context, serverURL string, path string, form url.Values, out interface{}) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(serverURL, "/")+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}